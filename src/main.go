@@ -0,0 +1,842 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+)
+
+const defaultBaseURL = "https://api.notefile.net/v1"
+
+// ProgressFunc is invoked as firmware bytes are streamed to Notehub, with
+// sent tracking the cumulative bytes written and total the size of the
+// firmware file.
+type ProgressFunc func(sent, total int64)
+
+// NotehubClient talks to the Notehub API on behalf of the action.
+type NotehubClient struct {
+	baseURL     string
+	httpClient  *http.Client
+	retryClient *retryablehttp.Client
+	accessToken string
+
+	// ProgressFunc reports UploadFirmware progress. It defaults to a
+	// logger that prints a line every 10% complete.
+	ProgressFunc ProgressFunc
+}
+
+// DeploymentConfig captures the inputs used to authenticate, upload
+// firmware, and target devices for a DFU.
+type DeploymentConfig struct {
+	ProjectUID   string
+	FirmwareFile string
+	ClientID     string
+	ClientSecret string
+
+	DeviceUID        string
+	Tag              string
+	SerialNumber     string
+	FleetUID         string
+	ProductUID       string
+	NotecardFirmware string
+	Location         string
+	SKU              string
+
+	// MaxRetries, RetryWaitMin, and RetryWaitMax tune the retry behavior
+	// of the underlying HTTP transport. They default to NewNotehubClient's
+	// built-in values when left unset.
+	MaxRetries   int
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+
+	// DryRun, when set, makes TriggerDFU resolve and log the devices a
+	// request would target instead of actually triggering a DFU.
+	DryRun bool
+}
+
+// operationKey tags a request's context with the Notehub operation that
+// produced it, so notehubCheckRetry can decide whether re-issuing it is
+// safe.
+type operationKey struct{}
+
+const (
+	opAuth    = "auth"
+	opTrigger = "trigger"
+	opUpload  = "upload"
+)
+
+func withOperation(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, operationKey{}, op)
+}
+
+func operationFromContext(ctx context.Context) string {
+	op, _ := ctx.Value(operationKey{}).(string)
+	return op
+}
+
+// notehubCheckRetry restricts retries to operations that are safe to
+// re-issue: GET requests, auth refreshes, and DFU triggers are idempotent
+// from Notehub's point of view, while a firmware upload is only retried
+// when the server reports a resumable-friendly status (429/503) rather
+// than a failure that may have landed server-side already.
+func notehubCheckRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	switch operationFromContext(ctx) {
+	case opUpload:
+		// A transport error (resp == nil) means we can't tell whether the
+		// body already landed server-side, so it's not safe to resend -
+		// only an explicit 429/503 response is treated as retryable.
+		if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+			return false, nil
+		}
+	case opAuth, opTrigger:
+		// idempotent; fall through to the default policy below.
+	default:
+		if resp != nil && resp.Request != nil && resp.Request.Method != http.MethodGet {
+			return false, nil
+		}
+	}
+
+	return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+}
+
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// projectPath joins the client's base URL with "projects/{projectUID}"
+// plus any additional path segments, using url.URL's relative-style
+// joining rather than string concatenation. This keeps a base URL with
+// its own path prefix (e.g. https://notehub.internal.corp/api/v1)
+// composing correctly regardless of trailing slashes.
+func (c *NotehubClient) projectPath(projectUID string, segments ...string) (string, error) {
+	if strings.ContainsAny(projectUID, "/\\") || strings.Contains(projectUID, "..") {
+		return "", fmt.Errorf("invalid project UID: %q", projectUID)
+	}
+
+	elems := append([]string{"projects", projectUID}, segments...)
+	joined, err := url.JoinPath(c.baseURL, elems...)
+	if err != nil {
+		return "", fmt.Errorf("failed to build request URL: %w", err)
+	}
+	return joined, nil
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// ClientOptions configures a NotehubClient's target API base URL and
+// retry behavior.
+type ClientOptions struct {
+	// BaseURL overrides the default public Notehub API. Falls back to the
+	// NOTEHUB_API_URL env var, then to defaultBaseURL.
+	BaseURL string
+
+	MaxRetries   int
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+}
+
+// NewNotehubClient builds a client against the public Notehub API, or
+// against NOTEHUB_API_URL when that env var is set, with a retryable
+// transport. MaxRetries, RetryWaitMin, and RetryWaitMax can be
+// tuned via the NOTEHUB_MAX_RETRIES, NOTEHUB_RETRY_WAIT_MIN, and
+// NOTEHUB_RETRY_WAIT_MAX env vars, which mirror the fields of the same
+// name on DeploymentConfig.
+func NewNotehubClient() *NotehubClient {
+	return NewNotehubClientWithOptions(ClientOptions{})
+}
+
+// NewNotehubClientWithOptions builds a client whose API base URL and
+// retry behavior can be overridden, e.g. to target a self-hosted or
+// staging Notehub instance via ClientOptions.BaseURL or NOTEHUB_API_URL.
+// Any zero-valued field falls back to its env var, then to
+// NewNotehubClient's defaults.
+func NewNotehubClientWithOptions(opts ClientOptions) *NotehubClient {
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("NOTEHUB_API_URL")
+	}
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	maxRetries := opts.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = envInt("NOTEHUB_MAX_RETRIES", 3)
+	}
+	retryWaitMin := opts.RetryWaitMin
+	if retryWaitMin == 0 {
+		retryWaitMin = envDuration("NOTEHUB_RETRY_WAIT_MIN", 1*time.Second)
+	}
+	retryWaitMax := opts.RetryWaitMax
+	if retryWaitMax == 0 {
+		retryWaitMax = envDuration("NOTEHUB_RETRY_WAIT_MAX", 30*time.Second)
+	}
+
+	return newNotehubClient(baseURL, retryOptions{
+		MaxRetries:   maxRetries,
+		RetryWaitMin: retryWaitMin,
+		RetryWaitMax: retryWaitMax,
+	})
+}
+
+type retryOptions struct {
+	MaxRetries   int
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+}
+
+// jitteredBackoff wraps retryablehttp's exponential DefaultBackoff with
+// randomized jitter, so that concurrent runners hitting the same rate
+// limit don't all wake up and retry in lockstep. A server-specified
+// Retry-After is honored as-is, since jittering it would undercut the
+// server's own pacing.
+func jitteredBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	wait := retryablehttp.DefaultBackoff(min, max, attemptNum, resp)
+	if resp != nil && resp.Header.Get("Retry-After") != "" {
+		return wait
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func newNotehubClient(baseURL string, opts retryOptions) *NotehubClient {
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = opts.MaxRetries
+	retryClient.RetryWaitMin = opts.RetryWaitMin
+	retryClient.RetryWaitMax = opts.RetryWaitMax
+	retryClient.CheckRetry = notehubCheckRetry
+	retryClient.Backoff = jitteredBackoff
+	retryClient.Logger = nil
+
+	httpClient := retryClient.StandardClient()
+	httpClient.Timeout = 30 * time.Second
+
+	return &NotehubClient{
+		baseURL:      baseURL,
+		httpClient:   httpClient,
+		retryClient:  retryClient,
+		ProgressFunc: defaultProgressLogger(),
+	}
+}
+
+// defaultProgressLogger returns a ProgressFunc that prints an
+// "Upload progress" line to stdout every time another 10% of the firmware
+// file has been sent.
+func defaultProgressLogger() ProgressFunc {
+	lastDecile := -1
+	return func(sent, total int64) {
+		if total <= 0 {
+			return
+		}
+		percent := int(float64(sent) / float64(total) * 100)
+		decile := percent / 10
+		if decile == lastDecile && percent != 100 {
+			return
+		}
+		lastDecile = decile
+		fmt.Printf("Upload progress: %d%% (%d/%d bytes)\n", percent, sent, total)
+	}
+}
+
+type authResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Authenticate exchanges the project's client credentials for an access
+// token used by subsequent requests.
+func (c *NotehubClient) Authenticate(ctx context.Context, clientID, clientSecret string) error {
+	if clientID == "" || clientSecret == "" {
+		return fmt.Errorf("client ID and client secret are required")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+
+	tokenURL, err := url.JoinPath(c.baseURL, "oauth2", "token")
+	if err != nil {
+		return fmt.Errorf("failed to build authentication request: %w", err)
+	}
+
+	ctx = withOperation(ctx, opAuth)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build authentication request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("authentication request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read authentication response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("authentication failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var authResp authResponse
+	if err := json.Unmarshal(body, &authResp); err != nil {
+		return fmt.Errorf("failed to parse authentication response: %w", err)
+	}
+
+	c.accessToken = authResp.AccessToken
+	return nil
+}
+
+type uploadResponse struct {
+	UID string `json:"uid"`
+}
+
+// streamFirmwarePart copies r into the multipart form field "file" in
+// fixed-size chunks, reporting cumulative progress through progress as it
+// goes. Memory usage stays constant regardless of the firmware's size.
+func streamFirmwarePart(writer *multipart.Writer, r io.Reader, filename string, total int64, progress ProgressFunc) error {
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return fmt.Errorf("failed to create multipart form: %w", err)
+	}
+
+	buf := make([]byte, 32*1024)
+	var sent int64
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if _, werr := part.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			sent += int64(n)
+			if progress != nil {
+				progress(sent, total)
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// sha256File returns the hex-encoded SHA-256 checksum of the file at
+// path, streaming it through the hasher rather than reading it into
+// memory in one shot.
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// UploadFirmware streams the firmware binary at firmwareFile to the given
+// project and returns the UID Notehub assigned to the uploaded artifact.
+// The file is read from disk in fixed-size chunks rather than buffered in
+// memory, and a SHA-256 checksum of its contents is sent alongside the
+// upload so the server can verify the artifact.
+func (c *NotehubClient) UploadFirmware(ctx context.Context, projectUID, firmwareFile string) (string, error) {
+	stat, err := os.Stat(firmwareFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read firmware file: %w", err)
+	}
+	total := stat.Size()
+
+	checksum, err := sha256File(firmwareFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum firmware file: %w", err)
+	}
+
+	// The multipart boundary is fixed up front and reused by every retry
+	// attempt's body, so the Content-Type header set below stays valid no
+	// matter how many times bodyFunc is invoked.
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	contentType := "multipart/form-data; boundary=" + boundary
+	filename := filepath.Base(firmwareFile)
+
+	// bodyFunc opens the firmware file and streams it into a fresh
+	// multipart pipe each time it's called, so retryablehttp never has to
+	// buffer the file into memory to make it replayable across attempts.
+	bodyFunc := retryablehttp.ReaderFunc(func() (io.Reader, error) {
+		file, err := os.Open(firmwareFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read firmware file: %w", err)
+		}
+
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		if err := writer.SetBoundary(boundary); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to set multipart boundary: %w", err)
+		}
+
+		go func() {
+			defer file.Close()
+			sendErr := streamFirmwarePart(writer, file, filename, total, c.ProgressFunc)
+			if closeErr := writer.Close(); closeErr != nil && sendErr == nil {
+				sendErr = closeErr
+			}
+			pw.CloseWithError(sendErr)
+		}()
+
+		return pr, nil
+	})
+
+	uploadURL, err := c.projectPath(projectUID, "firmware")
+	if err != nil {
+		return "", err
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(withOperation(ctx, opUpload), http.MethodPost, uploadURL, bodyFunc)
+	if err != nil {
+		return "", fmt.Errorf("failed to build upload request: %w", err)
+	}
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("X-Firmware-SHA256", checksum)
+
+	resp, err := c.retryClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("upload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("firmware upload failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var uploadResp uploadResponse
+	if err := json.Unmarshal(body, &uploadResp); err != nil {
+		return "", fmt.Errorf("failed to parse upload response: %w", err)
+	}
+
+	return uploadResp.UID, nil
+}
+
+// addCommaSeparatedParams splits value on commas, trims whitespace around
+// each segment, and adds every non-empty segment to params under
+// paramName. Empty segments (from trailing/doubled commas) are skipped.
+func addCommaSeparatedParams(params url.Values, paramName, value string) {
+	if value == "" {
+		return
+	}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			params.Add(paramName, v)
+		}
+	}
+}
+
+// selectorQueryParams builds the comma-separated selector query params
+// shared by TriggerDFU, WatchDFU, and dry-run device resolution, so a
+// user's filter set is identical across all three.
+func selectorQueryParams(config *DeploymentConfig) url.Values {
+	queryParams := url.Values{}
+	addCommaSeparatedParams(queryParams, "deviceUID", config.DeviceUID)
+	addCommaSeparatedParams(queryParams, "tags", config.Tag)
+	addCommaSeparatedParams(queryParams, "serialNumber", config.SerialNumber)
+	addCommaSeparatedParams(queryParams, "fleetUID", config.FleetUID)
+	addCommaSeparatedParams(queryParams, "productUID", config.ProductUID)
+	addCommaSeparatedParams(queryParams, "notecardFirmware", config.NotecardFirmware)
+	addCommaSeparatedParams(queryParams, "location", config.Location)
+	addCommaSeparatedParams(queryParams, "sku", config.SKU)
+	return queryParams
+}
+
+// dfuTriggerURL builds the complete DFU trigger URL and query params for
+// config against the client's base URL.
+func (c *NotehubClient) dfuTriggerURL(config *DeploymentConfig) (string, url.Values, error) {
+	queryParams := selectorQueryParams(config)
+
+	dfuURL, err := c.projectPath(config.ProjectUID, "dfu", "host", "update")
+	if err != nil {
+		return "", nil, err
+	}
+	if len(queryParams) > 0 {
+		dfuURL += "?" + queryParams.Encode()
+	}
+
+	return dfuURL, queryParams, nil
+}
+
+// TriggerDFU requests a DFU for every device matched by config's
+// selectors, using the given firmware UID.
+func (c *NotehubClient) TriggerDFU(ctx context.Context, config *DeploymentConfig, firmwareUID string) error {
+	dfuURL, queryParams, err := c.dfuTriggerURL(config)
+	if err != nil {
+		return err
+	}
+
+	if config.DryRun {
+		devices, err := c.resolveDevices(ctx, config.ProjectUID, queryParams)
+		if err != nil {
+			return fmt.Errorf("failed to resolve devices for dry run: %w", err)
+		}
+
+		fmt.Printf("[dry-run] DFU URL: %s\n", dfuURL)
+		fmt.Printf("[dry-run] %d device(s) matched: %s\n", len(devices), strings.Join(devices, ", "))
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]string{"firmware": firmwareUID})
+	if err != nil {
+		return fmt.Errorf("failed to encode DFU payload: %w", err)
+	}
+
+	ctx = withOperation(ctx, opTrigger)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dfuURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build DFU trigger request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("DFU trigger request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("DFU trigger failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// DFUDeviceStatus is the current state of a single device's DFU as
+// reported by the Notehub status endpoint.
+type DFUDeviceStatus struct {
+	DeviceUID string `json:"device_uid"`
+	State     string `json:"state"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DFUStatusSummary aggregates per-device DFU state from a WatchDFU poll.
+type DFUStatusSummary struct {
+	Devices   []DFUDeviceStatus
+	Completed int
+	Errored   int
+	Cancelled int
+}
+
+// WatchDFUOptions configures WatchDFU's polling loop.
+type WatchDFUOptions struct {
+	// PollInterval is the time between status checks. Defaults to 10s.
+	PollInterval time.Duration
+	// Deadline is the overall time WatchDFU will wait for every matched
+	// device to reach a terminal state before giving up. Defaults to 30m.
+	Deadline time.Duration
+}
+
+const (
+	dfuStateCompleted = "completed"
+	dfuStateError     = "error"
+	dfuStateCancelled = "cancelled"
+)
+
+// WatchDFU polls the DFU status endpoint for the devices matched by
+// config's selectors - reusing the same selectorQueryParams logic
+// TriggerDFU used to select them - until every matched device reaches a
+// terminal state or opts.Deadline elapses. It returns a non-nil error if
+// any device ends in the error state, or immediately if the first poll
+// finds no matched devices at all.
+func (c *NotehubClient) WatchDFU(ctx context.Context, config *DeploymentConfig, opts WatchDFUOptions) (*DFUStatusSummary, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 10 * time.Second
+	}
+	if opts.Deadline <= 0 {
+		opts.Deadline = 30 * time.Minute
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Deadline)
+	defer cancel()
+
+	queryParams := selectorQueryParams(config)
+
+	statusURL, err := c.projectPath(config.ProjectUID, "dfu", "host", "status")
+	if err != nil {
+		return nil, err
+	}
+	if len(queryParams) > 0 {
+		statusURL += "?" + queryParams.Encode()
+	}
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	for first := true; ; first = false {
+		summary, err := c.fetchDFUStatus(ctx, statusURL)
+		if err != nil {
+			return nil, err
+		}
+
+		// An empty device set on the very first poll means config's
+		// selectors didn't match anything - that's a selector mistake, not
+		// a device that hasn't reported in yet, so it's reported
+		// immediately rather than burning the full deadline waiting for
+		// devices that will never appear.
+		if first && len(summary.Devices) == 0 {
+			return summary, fmt.Errorf("no devices matched the given selectors")
+		}
+
+		terminal := summary.Completed + summary.Errored + summary.Cancelled
+		fmt.Printf("DFU status: %d/%d terminal (%d completed, %d errored, %d cancelled)\n",
+			terminal, len(summary.Devices), summary.Completed, summary.Errored, summary.Cancelled)
+
+		if len(summary.Devices) > 0 && terminal == len(summary.Devices) {
+			if summary.Errored > 0 {
+				return summary, fmt.Errorf("%d device(s) failed DFU", summary.Errored)
+			}
+			return summary, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return summary, fmt.Errorf("timed out waiting for DFU to complete: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchDFUStatus performs a single poll against statusURL and aggregates
+// the response into a DFUStatusSummary.
+func (c *NotehubClient) fetchDFUStatus(ctx context.Context, statusURL string) (*DFUStatusSummary, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build DFU status request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DFU status request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DFU status response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DFU status request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var statusResp struct {
+		Devices []DFUDeviceStatus `json:"devices"`
+	}
+	if err := json.Unmarshal(body, &statusResp); err != nil {
+		return nil, fmt.Errorf("failed to parse DFU status response: %w", err)
+	}
+
+	summary := &DFUStatusSummary{Devices: statusResp.Devices}
+	for _, d := range statusResp.Devices {
+		switch d.State {
+		case dfuStateCompleted:
+			summary.Completed++
+		case dfuStateError:
+			summary.Errored++
+		case dfuStateCancelled:
+			summary.Cancelled++
+		}
+	}
+
+	return summary, nil
+}
+
+// resolveDevices performs a dry-run lookup of the devices matched by
+// queryParams against Notehub's device-list endpoint, without triggering
+// anything.
+func (c *NotehubClient) resolveDevices(ctx context.Context, projectUID string, queryParams url.Values) ([]string, error) {
+	devicesURL, err := c.projectPath(projectUID, "devices")
+	if err != nil {
+		return nil, err
+	}
+	if len(queryParams) > 0 {
+		devicesURL += "?" + queryParams.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, devicesURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build device list request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("device list request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device list response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device list request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var listResp struct {
+		Devices []struct {
+			UID string `json:"uid"`
+		} `json:"devices"`
+	}
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("failed to parse device list response: %w", err)
+	}
+
+	uids := make([]string, 0, len(listResp.Devices))
+	for _, d := range listResp.Devices {
+		uids = append(uids, d.UID)
+	}
+
+	return uids, nil
+}
+
+func main() {
+	config := &DeploymentConfig{
+		ProjectUID:   os.Getenv("INPUT_PROJECT_UID"),
+		FirmwareFile: os.Getenv("INPUT_FIRMWARE_FILE"),
+		ClientID:     os.Getenv("INPUT_CLIENT_ID"),
+		ClientSecret: os.Getenv("INPUT_CLIENT_SECRET"),
+
+		DeviceUID:        os.Getenv("INPUT_DEVICE_UID"),
+		Tag:              os.Getenv("INPUT_TAG"),
+		SerialNumber:     os.Getenv("INPUT_SERIAL_NUMBER"),
+		FleetUID:         os.Getenv("INPUT_FLEET_UID"),
+		ProductUID:       os.Getenv("INPUT_PRODUCT_UID"),
+		NotecardFirmware: os.Getenv("INPUT_NOTECARD_FIRMWARE"),
+		Location:         os.Getenv("INPUT_LOCATION"),
+		SKU:              os.Getenv("INPUT_SKU"),
+
+		MaxRetries:   envInt("INPUT_MAX_RETRIES", 0),
+		RetryWaitMin: envDuration("INPUT_RETRY_WAIT_MIN", 0),
+		RetryWaitMax: envDuration("INPUT_RETRY_WAIT_MAX", 0),
+
+		DryRun: os.Getenv("INPUT_DRY_RUN") == "true",
+	}
+
+	client := NewNotehubClientWithOptions(ClientOptions{
+		BaseURL:      os.Getenv("INPUT_NOTEHUB_API_URL"),
+		MaxRetries:   config.MaxRetries,
+		RetryWaitMin: config.RetryWaitMin,
+		RetryWaitMax: config.RetryWaitMax,
+	})
+	ctx := context.Background()
+
+	if err := client.Authenticate(ctx, config.ClientID, config.ClientSecret); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if manifestFile := os.Getenv("INPUT_MANIFEST_FILE"); manifestFile != "" {
+		manifest, err := loadDeploymentManifest(manifestFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		results := runManifest(ctx, client, config.ProjectUID, manifest)
+		if err := writeJobSummary(results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+
+		for _, r := range results {
+			if r.Err != nil {
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	var firmwareUID string
+	if !config.DryRun {
+		uid, err := client.UploadFirmware(ctx, config.ProjectUID, config.FirmwareFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		firmwareUID = uid
+	}
+
+	if err := client.TriggerDFU(ctx, config, firmwareUID); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !config.DryRun {
+		fmt.Println("DFU triggered successfully")
+	}
+
+	if os.Getenv("INPUT_WAIT_FOR_COMPLETION") == "true" {
+		if _, err := client.WatchDFU(ctx, config, WatchDFUOptions{
+			PollInterval: envDuration("INPUT_POLL_INTERVAL", 10*time.Second),
+			Deadline:     envDuration("INPUT_DEADLINE", 30*time.Minute),
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -326,9 +331,9 @@ func TestTriggerDFU_CommaSeparatedQueryParams(t *testing.T) {
 
 func TestTriggerDFU_CompleteURLGeneration(t *testing.T) {
 	tests := []struct {
-		name        string
-		config      *DeploymentConfig
-		expectedURL string
+		name         string
+		config       *DeploymentConfig
+		expectedPath string
 	}{
 		{
 			name: "single tag",
@@ -336,7 +341,7 @@ func TestTriggerDFU_CompleteURLGeneration(t *testing.T) {
 				ProjectUID: "app:12345678-1234-1234-1234-123456789012",
 				Tag:        "production",
 			},
-			expectedURL: "https://api.notefile.net/v1/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?tags=production",
+			expectedPath: "/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?tags=production",
 		},
 		{
 			name: "multiple tags",
@@ -344,7 +349,7 @@ func TestTriggerDFU_CompleteURLGeneration(t *testing.T) {
 				ProjectUID: "app:12345678-1234-1234-1234-123456789012",
 				Tag:        "production,sensor,outdoor",
 			},
-			expectedURL: "https://api.notefile.net/v1/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?tags=production&tags=sensor&tags=outdoor",
+			expectedPath: "/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?tags=production&tags=sensor&tags=outdoor",
 		},
 		{
 			name: "multiple device UIDs",
@@ -352,7 +357,7 @@ func TestTriggerDFU_CompleteURLGeneration(t *testing.T) {
 				ProjectUID: "app:12345678-1234-1234-1234-123456789012",
 				DeviceUID:  "device-123,device-456,device-789",
 			},
-			expectedURL: "https://api.notefile.net/v1/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?deviceUID=device-123&deviceUID=device-456&deviceUID=device-789",
+			expectedPath: "/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?deviceUID=device-123&deviceUID=device-456&deviceUID=device-789",
 		},
 		{
 			name: "mixed parameters with comma-separated values",
@@ -363,7 +368,7 @@ func TestTriggerDFU_CompleteURLGeneration(t *testing.T) {
 				SerialNumber: "SN001",
 				FleetUID:     "fleet-A,fleet-B",
 			},
-			expectedURL: "https://api.notefile.net/v1/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?deviceUID=device-123&deviceUID=device-456&fleetUID=fleet-A&fleetUID=fleet-B&serialNumber=SN001&tags=production&tags=sensor",
+			expectedPath: "/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?deviceUID=device-123&deviceUID=device-456&fleetUID=fleet-A&fleetUID=fleet-B&serialNumber=SN001&tags=production&tags=sensor",
 		},
 		{
 			name: "all parameters with multiple values",
@@ -378,38 +383,227 @@ func TestTriggerDFU_CompleteURLGeneration(t *testing.T) {
 				Location:         "loc1,loc2",
 				SKU:              "sku1,sku2",
 			},
-			expectedURL: "https://api.notefile.net/v1/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?deviceUID=dev1&deviceUID=dev2&fleetUID=fleet1&fleetUID=fleet2&location=loc1&location=loc2&notecardFirmware=fw1&notecardFirmware=fw2&productUID=prod1&productUID=prod2&serialNumber=SN1&serialNumber=SN2&sku=sku1&sku=sku2&tags=tag1&tags=tag2",
+			expectedPath: "/projects/app:12345678-1234-1234-1234-123456789012/dfu/host/update?deviceUID=dev1&deviceUID=dev2&fleetUID=fleet1&fleetUID=fleet2&location=loc1&location=loc2&notecardFirmware=fw1&notecardFirmware=fw2&productUID=prod1&productUID=prod2&serialNumber=SN1&serialNumber=SN2&sku=sku1&sku=sku2&tags=tag1&tags=tag2",
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Build query parameters using the same logic as TriggerDFU
-			queryParams := url.Values{}
+	// Exercised against several base URLs - including one with a trailing
+	// slash and one with its own path prefix - to verify the URL joining
+	// in NewNotehubClientWithOptions/projectPath composes correctly
+	// rather than relying on string concatenation.
+	baseURLs := []string{
+		"https://api.notefile.net/v1",
+		"https://api.notefile.net/v1/",
+		"https://notehub.internal.corp/api/v1",
+	}
 
-			addCommaSeparatedParams(queryParams, "deviceUID", tt.config.DeviceUID)
-			addCommaSeparatedParams(queryParams, "tags", tt.config.Tag)
-			addCommaSeparatedParams(queryParams, "serialNumber", tt.config.SerialNumber)
-			addCommaSeparatedParams(queryParams, "fleetUID", tt.config.FleetUID)
-			addCommaSeparatedParams(queryParams, "productUID", tt.config.ProductUID)
-			addCommaSeparatedParams(queryParams, "notecardFirmware", tt.config.NotecardFirmware)
-			addCommaSeparatedParams(queryParams, "location", tt.config.Location)
-			addCommaSeparatedParams(queryParams, "sku", tt.config.SKU)
+	for _, baseURL := range baseURLs {
+		trimmedBaseURL := strings.TrimRight(baseURL, "/")
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/%s", baseURL, tt.name), func(t *testing.T) {
+				client := NewNotehubClientWithOptions(ClientOptions{BaseURL: baseURL})
 
-			// Build the complete URL
-			baseURL := "https://api.notefile.net/v1"
-			dfuURL := fmt.Sprintf("%s/projects/%s/dfu/host/update", baseURL, tt.config.ProjectUID)
-			if len(queryParams) > 0 {
-				dfuURL += "?" + queryParams.Encode()
-			}
+				dfuURL, _, err := client.dfuTriggerURL(tt.config)
+				if err != nil {
+					t.Fatalf("dfuTriggerURL returned error: %v", err)
+				}
 
-			// Verify the complete URL matches expected
-			if dfuURL != tt.expectedURL {
-				t.Errorf("URL mismatch:\nExpected: %s\nActual:   %s", tt.expectedURL, dfuURL)
-			}
+				expectedURL := trimmedBaseURL + tt.expectedPath
+				if dfuURL != expectedURL {
+					t.Errorf("URL mismatch:\nExpected: %s\nActual:   %s", expectedURL, dfuURL)
+				}
 
-			// Also log the URL for visual verification
-			t.Logf("Generated URL: %s", dfuURL)
-		})
+				// Also log the URL for visual verification
+				t.Logf("Generated URL: %s", dfuURL)
+			})
+		}
+	}
+}
+
+// TestUploadFirmware_StreamsWithoutBuffering verifies that the request
+// body retryablehttp sends is rebuilt and re-streamed from disk rather
+// than buffered into memory, by failing the first attempt and checking
+// that the server still receives the full, correct firmware bytes on the
+// retry.
+func TestUploadFirmware_StreamsWithoutBuffering(t *testing.T) {
+	firmware := bytes.Repeat([]byte("abcdefgh"), 64*1024) // 512KB
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			// Simulate a transient failure before the body is even read,
+			// forcing retryablehttp to rebuild and resend the request.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("failed to read multipart body: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Errorf("failed to read multipart part: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		got, err := io.ReadAll(part)
+		if err != nil {
+			t.Errorf("failed to read firmware part: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !bytes.Equal(got, firmware) {
+			t.Errorf("uploaded firmware mismatch: got %d bytes, want %d bytes", len(got), len(firmware))
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"uid":"firmware-123"}`))
+	}))
+	defer server.Close()
+
+	testFile := "test-firmware-stream.bin"
+	if err := os.WriteFile(testFile, firmware, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	defer os.Remove(testFile)
+
+	client := newNotehubClient(server.URL, retryOptions{MaxRetries: 1, RetryWaitMin: time.Millisecond, RetryWaitMax: time.Millisecond})
+	client.accessToken = "test-token"
+
+	uid, err := client.UploadFirmware(context.Background(), "test-project", testFile)
+	if err != nil {
+		t.Fatalf("UploadFirmware returned error: %v", err)
+	}
+	if uid != "firmware-123" {
+		t.Errorf("Expected uid 'firmware-123', got %q", uid)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts (one retry), got %d", attempts)
+	}
+}
+
+// TestWatchDFU_PollsUntilTerminal verifies that WatchDFU keeps polling
+// while devices are still pending and returns successfully once every
+// matched device reaches a terminal state.
+func TestWatchDFU_PollsUntilTerminal(t *testing.T) {
+	var polls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		state := "pending"
+		if polls >= 3 {
+			state = "completed"
+		}
+		fmt.Fprintf(w, `{"devices":[{"device_uid":"dev-1","state":%q}]}`, state)
+	}))
+	defer server.Close()
+
+	client := newNotehubClient(server.URL, retryOptions{})
+	client.accessToken = "test-token"
+
+	summary, err := client.WatchDFU(context.Background(), &DeploymentConfig{ProjectUID: "test-project"}, WatchDFUOptions{
+		PollInterval: time.Millisecond,
+		Deadline:     time.Second,
+	})
+	if err != nil {
+		t.Fatalf("WatchDFU returned error: %v", err)
+	}
+	if summary.Completed != 1 {
+		t.Errorf("Expected 1 completed device, got %d", summary.Completed)
+	}
+	if polls < 3 {
+		t.Errorf("Expected WatchDFU to poll until completion, only polled %d times", polls)
+	}
+}
+
+// TestWatchDFU_ErroredDevice verifies that a device ending in the error
+// state surfaces as a non-nil error once the matched devices are all
+// terminal.
+func TestWatchDFU_ErroredDevice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"devices":[{"device_uid":"dev-1","state":"error","error":"flash failed"}]}`)
+	}))
+	defer server.Close()
+
+	client := newNotehubClient(server.URL, retryOptions{})
+	client.accessToken = "test-token"
+
+	_, err := client.WatchDFU(context.Background(), &DeploymentConfig{ProjectUID: "test-project"}, WatchDFUOptions{
+		PollInterval: time.Millisecond,
+		Deadline:     time.Second,
+	})
+	if err == nil {
+		t.Fatal("Expected WatchDFU to return an error for a failed device")
+	}
+}
+
+// TestWatchDFU_NoMatchedDevices verifies that an empty device set on the
+// first poll is reported as an error right away - neither a false
+// success nor a 30-minute wait for devices that will never appear.
+func TestWatchDFU_NoMatchedDevices(t *testing.T) {
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		fmt.Fprint(w, `{"devices":[]}`)
+	}))
+	defer server.Close()
+
+	client := newNotehubClient(server.URL, retryOptions{})
+	client.accessToken = "test-token"
+
+	start := time.Now()
+	_, err := client.WatchDFU(context.Background(), &DeploymentConfig{ProjectUID: "test-project"}, WatchDFUOptions{
+		PollInterval: time.Millisecond,
+		Deadline:     time.Minute,
+	})
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Expected WatchDFU to report an error for a selector matching no devices")
+	}
+	if elapsed >= time.Minute {
+		t.Errorf("Expected WatchDFU to fail fast on an empty first poll, took %v", elapsed)
+	}
+	if atomic.LoadInt32(&polls) != 1 {
+		t.Errorf("Expected exactly 1 poll before failing, got %d", polls)
+	}
+}
+
+// TestTriggerDFU_DryRun verifies that DryRun resolves and logs the
+// matched devices via the device-list endpoint instead of posting a DFU
+// trigger request.
+func TestTriggerDFU_DryRun(t *testing.T) {
+	var triggered bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/devices"):
+			fmt.Fprint(w, `{"devices":[{"uid":"dev-1"},{"uid":"dev-2"}]}`)
+		case strings.Contains(r.URL.Path, "/dfu/host/update"):
+			triggered = true
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newNotehubClient(server.URL, retryOptions{})
+	client.accessToken = "test-token"
+
+	config := &DeploymentConfig{
+		ProjectUID: "test-project",
+		Tag:        "production",
+		DryRun:     true,
+	}
+
+	if err := client.TriggerDFU(context.Background(), config, "firmware-uid"); err != nil {
+		t.Fatalf("TriggerDFU returned error: %v", err)
+	}
+	if triggered {
+		t.Error("Expected DryRun to skip the DFU trigger request")
 	}
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestArtifact describes a single firmware artifact and the device
+// selectors it should be deployed to.
+type ManifestArtifact struct {
+	FirmwareFile string `yaml:"firmwareFile" json:"firmwareFile"`
+
+	DeviceUID        string `yaml:"deviceUID,omitempty" json:"deviceUID,omitempty"`
+	Tag              string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	SerialNumber     string `yaml:"serialNumber,omitempty" json:"serialNumber,omitempty"`
+	FleetUID         string `yaml:"fleetUID,omitempty" json:"fleetUID,omitempty"`
+	ProductUID       string `yaml:"productUID,omitempty" json:"productUID,omitempty"`
+	NotecardFirmware string `yaml:"notecardFirmware,omitempty" json:"notecardFirmware,omitempty"`
+	Location         string `yaml:"location,omitempty" json:"location,omitempty"`
+	SKU              string `yaml:"sku,omitempty" json:"sku,omitempty"`
+}
+
+// DeploymentManifest lists multiple firmware artifacts to deploy in a
+// single action run, loaded from a file such as dfu.yaml.
+type DeploymentManifest struct {
+	Artifacts []ManifestArtifact `yaml:"artifacts" json:"artifacts"`
+
+	// MaxRoutines bounds how many artifacts are uploaded and triggered
+	// concurrently. Defaults to 4.
+	MaxRoutines int `yaml:"maxRoutines,omitempty" json:"maxRoutines,omitempty"`
+
+	// FailFast stops launching remaining artifacts as soon as one fails.
+	// Defaults to false, so one bad artifact doesn't block the rest.
+	FailFast bool `yaml:"failFast,omitempty" json:"failFast,omitempty"`
+}
+
+// loadDeploymentManifest reads and parses a manifest file, choosing a
+// YAML or JSON decoder based on its extension.
+func loadDeploymentManifest(path string) (*DeploymentManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	manifest := &DeploymentManifest{}
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest file: %w", err)
+		}
+	} else if err := yaml.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest file: %w", err)
+	}
+
+	if manifest.MaxRoutines <= 0 {
+		manifest.MaxRoutines = 4
+	}
+
+	return manifest, nil
+}
+
+// ArtifactResult is the outcome of deploying a single manifest artifact.
+type ArtifactResult struct {
+	Artifact ManifestArtifact
+	Err      error
+}
+
+// runManifest uploads and triggers a DFU for every artifact in the
+// manifest, fanning the work out across a bounded worker pool. Unless
+// manifest.FailFast is set, a failing artifact doesn't stop the rest.
+func runManifest(ctx context.Context, client *NotehubClient, projectUID string, manifest *DeploymentManifest) []ArtifactResult {
+	results := make([]ArtifactResult, len(manifest.Artifacts))
+	sem := make(chan struct{}, manifest.MaxRoutines)
+	failed := make(chan struct{})
+	var failOnce sync.Once
+	var wg sync.WaitGroup
+
+	for i, artifact := range manifest.Artifacts {
+		sem <- struct{}{}
+
+		// Re-check failed after acquiring the semaphore, not before: a
+		// pool that's already saturated can block on the semaphore send
+		// above for a while, during which a running artifact might fail and close
+		// failed. Checking only once we're about to launch means a
+		// saturated pool still stops dispatching promptly.
+		if manifest.FailFast {
+			select {
+			case <-failed:
+				<-sem
+				results[i] = ArtifactResult{Artifact: artifact, Err: fmt.Errorf("skipped after an earlier failure")}
+				continue
+			default:
+			}
+		}
+
+		wg.Add(1)
+		go func(i int, artifact ManifestArtifact) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := deployArtifact(ctx, client, projectUID, artifact)
+			results[i] = ArtifactResult{Artifact: artifact, Err: err}
+			if err != nil {
+				fmt.Printf("Artifact %s failed: %v\n", artifact.FirmwareFile, err)
+				if manifest.FailFast {
+					failOnce.Do(func() { close(failed) })
+				}
+			}
+		}(i, artifact)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// deployArtifact uploads a single manifest artifact's firmware and
+// triggers a DFU against its selectors.
+func deployArtifact(ctx context.Context, client *NotehubClient, projectUID string, artifact ManifestArtifact) error {
+	config := &DeploymentConfig{
+		ProjectUID:       projectUID,
+		DeviceUID:        artifact.DeviceUID,
+		Tag:              artifact.Tag,
+		SerialNumber:     artifact.SerialNumber,
+		FleetUID:         artifact.FleetUID,
+		ProductUID:       artifact.ProductUID,
+		NotecardFirmware: artifact.NotecardFirmware,
+		Location:         artifact.Location,
+		SKU:              artifact.SKU,
+	}
+
+	firmwareUID, err := client.UploadFirmware(ctx, projectUID, artifact.FirmwareFile)
+	if err != nil {
+		return err
+	}
+
+	return client.TriggerDFU(ctx, config, firmwareUID)
+}
+
+// writeJobSummary appends a Markdown table of per-artifact results to the
+// file referenced by GITHUB_STEP_SUMMARY, if set.
+func writeJobSummary(results []ArtifactResult) error {
+	summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryPath == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job summary file: %w", err)
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "## DFU Deployment Summary")
+	fmt.Fprintln(f, "| Firmware | Status |")
+	fmt.Fprintln(f, "| --- | --- |")
+	for _, r := range results {
+		status := "✅ success"
+		if r.Err != nil {
+			status = fmt.Sprintf("❌ %v", r.Err)
+		}
+		fmt.Fprintf(f, "| %s | %s |\n", r.Artifact.FirmwareFile, status)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRunManifest_BoundedConcurrency verifies that runManifest never runs
+// more artifacts concurrently than manifest.MaxRoutines, while still
+// completing every artifact.
+func TestRunManifest_BoundedConcurrency(t *testing.T) {
+	const maxRoutines = 2
+	var inFlight int32
+	var maxObserved int32
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		mu.Lock()
+		if n > maxObserved {
+			maxObserved = n
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+
+		if r.Method == http.MethodPost && r.URL.Path == "/projects/test-project/firmware" {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"uid":"firmware-1"}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := newNotehubClient(server.URL, retryOptions{})
+	client.accessToken = "test-token"
+
+	testFile := "test-manifest-firmware.bin"
+	if err := os.WriteFile(testFile, []byte("firmware"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	defer os.Remove(testFile)
+
+	manifest := &DeploymentManifest{MaxRoutines: maxRoutines}
+	for i := 0; i < 6; i++ {
+		manifest.Artifacts = append(manifest.Artifacts, ManifestArtifact{
+			FirmwareFile: testFile,
+			DeviceUID:    fmt.Sprintf("device-%d", i),
+		})
+	}
+
+	results := runManifest(context.Background(), client, "test-project", manifest)
+
+	if len(results) != 6 {
+		t.Fatalf("Expected 6 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("Expected artifact to succeed, got error: %v", r.Err)
+		}
+	}
+	if maxObserved > maxRoutines {
+		t.Errorf("Expected at most %d concurrent artifacts, observed %d", maxRoutines, maxObserved)
+	}
+}
+
+// TestRunManifest_FailFast verifies that once an artifact fails,
+// FailFast skips launching the remaining ones instead of running them.
+func TestRunManifest_FailFast(t *testing.T) {
+	var launched int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&launched, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := newNotehubClient(server.URL, retryOptions{})
+	client.accessToken = "test-token"
+
+	testFile := "test-manifest-failfast.bin"
+	if err := os.WriteFile(testFile, []byte("firmware"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	defer os.Remove(testFile)
+
+	manifest := &DeploymentManifest{MaxRoutines: 1, FailFast: true}
+	for i := 0; i < 4; i++ {
+		manifest.Artifacts = append(manifest.Artifacts, ManifestArtifact{
+			FirmwareFile: testFile,
+			DeviceUID:    fmt.Sprintf("device-%d", i),
+		})
+	}
+
+	results := runManifest(context.Background(), client, "test-project", manifest)
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	for i, r := range results {
+		if r.Err == nil {
+			t.Errorf("Expected artifact %d to have failed, got nil error", i)
+		}
+	}
+	// MaxRoutines is 1, so the pool is fully saturated: the first artifact
+	// fails before the second is ever dispatched, and FailFast must skip
+	// every artifact after it rather than launching one more before
+	// noticing the failure.
+	if launched != 1 {
+		t.Errorf("Expected FailFast to launch exactly 1 artifact before stopping, launched=%d", launched)
+	}
+}
+
+// TestLoadDeploymentManifest_DefaultMaxRoutines verifies that a manifest
+// with no explicit maxRoutines falls back to the documented default of 4.
+func TestLoadDeploymentManifest_DefaultMaxRoutines(t *testing.T) {
+	data, err := json.Marshal(map[string]any{
+		"artifacts": []map[string]string{{"firmwareFile": "fw.bin"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal test manifest: %v", err)
+	}
+
+	path := "test-manifest.json"
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write test manifest: %v", err)
+	}
+	defer os.Remove(path)
+
+	manifest, err := loadDeploymentManifest(path)
+	if err != nil {
+		t.Fatalf("loadDeploymentManifest returned error: %v", err)
+	}
+	if manifest.MaxRoutines != 4 {
+		t.Errorf("Expected default MaxRoutines of 4, got %d", manifest.MaxRoutines)
+	}
+}